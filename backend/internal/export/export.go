@@ -0,0 +1,132 @@
+// Package export はスレッドを Markdown / HTML / zip としてダウンロード可能な形に組み立てる。
+// JSON 形式は SlackMessage をそのまま json.MarshalIndent するだけなので main パッケージ側で完結させている。
+package export
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ReactionSummary 1 つの絵文字について、付けた人数とその表示名をまとめたもの
+type ReactionSummary struct {
+	Emoji    string   `json:"emoji"`
+	Count    int      `json:"count"`
+	Reactors []string `json:"reactors"`
+}
+
+// Attachment 1 つのファイル添付の出力用表現
+// DataURI が設定されていれば本文にインライン埋め込み、ZipName が設定されていれば zip 同梱、
+// どちらも無ければ Note にその理由 (埋め込めなかった旨) を入れる。
+// url_private は Bot トークンでの認可が無いと開けないため、zip に同梱しない限りリンクとしては出さない
+type Attachment struct {
+	Name     string
+	Mimetype string
+	DataURI  string
+	ZipName  string
+	Note     string
+}
+
+// Message 1 メッセージ分の出力用表現。Body は既に render パッケージでフォーマット済みのテキスト
+type Message struct {
+	Timestamp   string
+	UserName    string
+	Body        string
+	Reactions   []ReactionSummary
+	Attachments []Attachment
+}
+
+// Markdown メッセージ一覧を「**Name** _timestamp_」ヘッダー + 引用ブロックの Markdown に変換する
+func Markdown(messages []Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		b.WriteString(fmt.Sprintf("**%s** _%s_\n", msg.UserName, msg.Timestamp))
+		for _, line := range strings.Split(msg.Body, "\n") {
+			b.WriteString("> " + line + "\n")
+		}
+
+		if len(msg.Reactions) > 0 {
+			b.WriteString("\nReactions: " + formatReactionsMarkdown(msg.Reactions) + "\n")
+		}
+		for _, a := range msg.Attachments {
+			b.WriteString("\n" + formatAttachmentMarkdown(a) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func formatReactionsMarkdown(reactions []ReactionSummary) string {
+	parts := make([]string, 0, len(reactions))
+	for _, r := range reactions {
+		parts = append(parts, fmt.Sprintf(":%s: %d (%s)", r.Emoji, r.Count, strings.Join(r.Reactors, ", ")))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatAttachmentMarkdown(a Attachment) string {
+	switch {
+	case a.DataURI != "":
+		return fmt.Sprintf("![%s](%s)", a.Name, a.DataURI)
+	case a.ZipName != "":
+		return fmt.Sprintf("[%s](attachments/%s)", a.Name, a.ZipName)
+	default:
+		return fmt.Sprintf("_%s (%s)_", a.Name, a.Note)
+	}
+}
+
+// embeddedCSS HTML エクスポートに埋め込む最小限のスタイル。外部ファイルに依存しない自己完結ページにするため
+const embeddedCSS = `
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 720px; margin: 2rem auto; color: #1d1c1d; }
+.message { border-bottom: 1px solid #e8e8e8; padding: 0.75rem 0; }
+.message .meta { font-size: 0.85rem; color: #616061; margin-bottom: 0.25rem; }
+.message .meta .user { font-weight: 700; color: #1d1c1d; }
+.message .body { white-space: pre-wrap; }
+.message .reactions { margin-top: 0.5rem; font-size: 0.85rem; color: #616061; }
+.message .attachments img { max-width: 100%; border-radius: 4px; margin-top: 0.5rem; }
+.message .attachments a { display: inline-block; margin-top: 0.5rem; }
+.message .attachments .attachment-note { display: block; margin-top: 0.5rem; font-style: italic; color: #616061; }
+`
+
+// HTML メッセージ一覧を CSS 埋め込み済みの自己完結 HTML ページに変換する
+func HTML(messages []Message) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>Slack thread export</title>\n<style>")
+	b.WriteString(embeddedCSS)
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	for _, msg := range messages {
+		b.WriteString("<div class=\"message\">\n")
+		b.WriteString(fmt.Sprintf(
+			"<div class=\"meta\"><span class=\"user\">%s</span> <span class=\"ts\">%s</span></div>\n",
+			html.EscapeString(msg.UserName), html.EscapeString(msg.Timestamp),
+		))
+		b.WriteString(fmt.Sprintf("<div class=\"body\">%s</div>\n", msg.Body))
+
+		if len(msg.Reactions) > 0 {
+			b.WriteString("<div class=\"reactions\">" + html.EscapeString(formatReactionsMarkdown(msg.Reactions)) + "</div>\n")
+		}
+		if len(msg.Attachments) > 0 {
+			b.WriteString("<div class=\"attachments\">\n")
+			for _, a := range msg.Attachments {
+				b.WriteString(formatAttachmentHTML(a))
+			}
+			b.WriteString("</div>\n")
+		}
+		b.WriteString("</div>\n")
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+func formatAttachmentHTML(a Attachment) string {
+	switch {
+	case a.DataURI != "" && strings.HasPrefix(a.Mimetype, "image/"):
+		return fmt.Sprintf(`<img src="%s" alt="%s"/>`, html.EscapeString(a.DataURI), html.EscapeString(a.Name))
+	case a.ZipName != "":
+		return fmt.Sprintf(`<a href="attachments/%s">%s</a>`, html.EscapeString(a.ZipName), html.EscapeString(a.Name))
+	default:
+		return fmt.Sprintf(`<span class="attachment-note">%s (%s)</span>`, html.EscapeString(a.Name), html.EscapeString(a.Note))
+	}
+}
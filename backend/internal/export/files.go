@@ -0,0 +1,141 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultMaxInlineBytes 画像添付を base64 data URI として本文に埋め込む際の上限サイズ
+const DefaultMaxInlineBytes int64 = 2 * 1024 * 1024
+
+// FileMeta エクスポート対象メッセージに添付されたファイルの最小限の情報
+type FileMeta struct {
+	ID       string
+	Name     string
+	Mimetype string
+}
+
+// PrepareAttachment files.info で最新のファイル情報を取得し、画像かつ maxInlineBytes 以下なら base64 data URI として
+// インライン埋め込みする。zip 出力が要求されている場合はサイズ・種類を問わずダウンロードして zip 同梱用のバイト列を返す。
+// どちらの条件にも合致しない場合、url_private は Bot トークンでの認可が無いと開けず外部リンクとして出しても無意味なので、
+// リンクの代わりに埋め込めなかった旨の Note を付けた Attachment を返す
+func PrepareAttachment(client *http.Client, botToken string, file FileMeta, maxInlineBytes int64, forZip bool) (Attachment, []byte, error) {
+	info, err := fetchFileInfo(client, botToken, file.ID)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+
+	isImage := strings.HasPrefix(info.Mimetype, "image/")
+	canInline := isImage && info.Size <= maxInlineBytes
+	if !canInline && !forZip {
+		note := "too large to embed; use the zip export to include this file"
+		if !isImage {
+			note = "not an image, so it can't be embedded inline; use the zip export to include this file"
+		}
+		return Attachment{Name: info.Name, Mimetype: info.Mimetype, Note: note}, nil, nil
+	}
+
+	data, err := downloadFile(client, botToken, info.URLPrivate)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+
+	if forZip {
+		return Attachment{Name: info.Name, Mimetype: info.Mimetype, ZipName: sanitizeZipName(info.ID, info.Name)}, data, nil
+	}
+
+	dataURI := fmt.Sprintf("data:%s;base64,%s", info.Mimetype, base64.StdEncoding.EncodeToString(data))
+	return Attachment{Name: info.Name, Mimetype: info.Mimetype, DataURI: dataURI}, nil, nil
+}
+
+// fileInfo files.info から取得する、ダウンロードに必要な最小限の情報
+type fileInfo struct {
+	ID         string
+	Name       string
+	Mimetype   string
+	Size       int64
+	URLPrivate string
+}
+
+func fetchFileInfo(client *http.Client, botToken, fileID string) (fileInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://slack.com/api/files.info", nil)
+	if err != nil {
+		return fileInfo{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+botToken)
+	query := url.Values{}
+	query.Set("file", fileID)
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fileInfo{}, fmt.Errorf("failed to call slack api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fileInfo{}, fmt.Errorf("slack api request failed with status: %s", resp.Status)
+	}
+
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		File  struct {
+			ID         string `json:"id"`
+			Name       string `json:"name"`
+			Mimetype   string `json:"mimetype"`
+			Size       int64  `json:"size"`
+			URLPrivate string `json:"url_private"`
+		} `json:"file"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fileInfo{}, fmt.Errorf("failed to decode slack api response: %w", err)
+	}
+	if !response.OK {
+		return fileInfo{}, fmt.Errorf("slack api returned an error: %s", response.Error)
+	}
+
+	return fileInfo{
+		ID:         response.File.ID,
+		Name:       response.File.Name,
+		Mimetype:   response.File.Mimetype,
+		Size:       response.File.Size,
+		URLPrivate: response.File.URLPrivate,
+	}, nil
+}
+
+// downloadFile url_private は Bot トークンでの認可が必要な署名無しの URL なので Authorization ヘッダを付けて取得する
+func downloadFile(client *http.Client, botToken, urlPrivate string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, urlPrivate, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+botToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("file download failed with status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file body: %w", err)
+	}
+	return data, nil
+}
+
+// sanitizeZipName 同名ファイルが複数あっても衝突しないよう、ファイル ID を接頭辞に付けた zip 内のパスを作る
+func sanitizeZipName(fileID, name string) string {
+	cleaned := strings.ReplaceAll(name, "/", "_")
+	return fmt.Sprintf("%s_%s", fileID, cleaned)
+}
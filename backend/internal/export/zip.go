@@ -0,0 +1,42 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+)
+
+// AttachmentFile zip に同梱するファイルの実体
+type AttachmentFile struct {
+	ZipName string
+	Data    []byte
+}
+
+// BuildZip transcript (Markdown 化したスレッド本文) と添付ファイルをまとめて 1 つの zip にする
+func BuildZip(transcriptName, transcript string, attachments []AttachmentFile) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+
+	transcriptWriter, err := writer.Create(transcriptName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip entry for transcript: %w", err)
+	}
+	if _, err := transcriptWriter.Write([]byte(transcript)); err != nil {
+		return nil, fmt.Errorf("failed to write transcript into zip: %w", err)
+	}
+
+	for _, a := range attachments {
+		fileWriter, err := writer.Create("attachments/" + a.ZipName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zip entry for %s: %w", a.ZipName, err)
+		}
+		if _, err := fileWriter.Write(a.Data); err != nil {
+			return nil, fmt.Errorf("failed to write attachment %s into zip: %w", a.ZipName, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize zip: %w", err)
+	}
+	return buf.Bytes(), nil
+}
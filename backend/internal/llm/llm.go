@@ -0,0 +1,145 @@
+// Package llm はスレッド要約に使う LLM バックエンドを切り替え可能な形でラップする。
+// デフォルトは OpenAI の Chat Completions だが、LLM_PROVIDER で差し替えられるようにしてある。
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Config LLM_PROVIDER / LLM_MODEL / LLM_API_KEY から読み取る設定
+type Config struct {
+	Provider string
+	Model    string
+	APIKey   string
+}
+
+// ConfigFromEnv 環境変数から Config を組み立てる。LLM_PROVIDER 未設定時は openai を使う
+func ConfigFromEnv() Config {
+	provider := os.Getenv("LLM_PROVIDER")
+	if provider == "" {
+		provider = "openai"
+	}
+	model := os.Getenv("LLM_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return Config{
+		Provider: provider,
+		Model:    model,
+		APIKey:   os.Getenv("LLM_API_KEY"),
+	}
+}
+
+// Provider prompt をトークン単位でストリーミングしながら LLM に投げるインターフェース
+// onDelta はトークン (または文の断片) が届くたびに呼ばれる
+type Provider interface {
+	Stream(ctx context.Context, prompt string, onDelta func(delta string) error) error
+}
+
+// NewProvider Config.Provider に応じた実装を返す。現状対応しているのは openai のみ
+func NewProvider(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "openai", "":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("LLM_API_KEY environment variable is not set")
+		}
+		return &openAIProvider{model: cfg.Model, apiKey: cfg.APIKey, httpClient: &http.Client{}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM_PROVIDER: %s", cfg.Provider)
+	}
+}
+
+// openAIProvider OpenAI Chat Completions API (stream=true) を使う実装
+type openAIProvider struct {
+	model      string
+	apiKey     string
+	httpClient *http.Client
+}
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Stream   bool          `json:"stream"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+func (p *openAIProvider) Stream(ctx context.Context, prompt string, onDelta func(delta string) error) error {
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:  p.model,
+		Stream: true,
+		Messages: []chatMessage{
+			{Role: "system", Content: "You are a concise assistant that summarizes Slack threads."},
+			{Role: "user", Content: prompt},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode chat completion request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call openai api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("openai api request failed with status: %s", resp.Status)
+	}
+
+	// OpenAI のストリーミングレスポンスは "data: {...}\n\n" 形式の Server-Sent Events
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read openai stream: %w", err)
+	}
+	return nil
+}
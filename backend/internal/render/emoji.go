@@ -0,0 +1,36 @@
+package render
+
+// EmojiTable よく使われる Slack 絵文字ショートコードから Unicode 絵文字への対応表
+// 未収録のショートコードは renderEmoji が ":name:" のまま返す
+var EmojiTable = map[string]string{
+	"+1":               "👍",
+	"-1":               "👎",
+	"thumbsup":         "👍",
+	"thumbsdown":       "👎",
+	"smile":            "😄",
+	"smiley":           "😃",
+	"grinning":         "😀",
+	"laughing":         "😆",
+	"joy":              "😂",
+	"wink":             "😉",
+	"heart":            "❤️",
+	"tada":             "🎉",
+	"fire":             "🔥",
+	"eyes":             "👀",
+	"pray":             "🙏",
+	"clap":             "👏",
+	"rocket":           "🚀",
+	"white_check_mark": "✅",
+	"heavy_check_mark": "✔️",
+	"x":                "❌",
+	"warning":          "⚠️",
+	"bug":              "🐛",
+	"sob":              "😭",
+	"thinking_face":    "🤔",
+	"point_up":         "☝️",
+	"wave":             "👋",
+	"100":              "💯",
+	"raised_hands":     "🙌",
+	"bow":              "🙇",
+	"muscle":           "💪",
+}
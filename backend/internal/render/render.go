@@ -0,0 +1,533 @@
+// Package render は Slack の mrkdwn / block kit を HTML・Markdown・プレーンテキストに変換する。
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Format 出力先のフォーマットを表す型
+type Format string
+
+const (
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+	FormatPlain    Format = "plain"
+)
+
+// ParseFormat クエリパラメータの文字列を Format に変換する。未知の値は plain にフォールバックする
+func ParseFormat(raw string) Format {
+	switch Format(raw) {
+	case FormatHTML, FormatMarkdown:
+		return Format(raw)
+	default:
+		return FormatPlain
+	}
+}
+
+// MentionResolver <@Uxxx> / <#Cxxx> / <!subteam^Sxxx> を表示名に解決するためのインターフェース
+// backend 側では slackclient の users.info / conversations.info / usergroups.list のラッパーを実装として渡す
+type MentionResolver interface {
+	UserName(userID string) (string, error)
+	ChannelName(channelID string) (string, error)
+	UsergroupName(usergroupID string) (string, error)
+}
+
+var (
+	userMentionRe      = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|([^>]*))?>`)
+	channelMentionRe   = regexp.MustCompile(`<#([A-Z0-9]+)(?:\|([^>]*))?>`)
+	usergroupMentionRe = regexp.MustCompile(`<!subteam\^([A-Z0-9]+)(?:\|([^>]*))?>`)
+	linkRe             = regexp.MustCompile(`<(https?:\/\/[^|>]+)(?:\|([^>]*))?>`)
+	emojiRe            = regexp.MustCompile(`:([a-zA-Z0-9_+\-]+):`)
+	codeBlockRe        = regexp.MustCompile("(?s)```(.*?)```")
+	inlineCodeRe       = regexp.MustCompile("`([^`]+)`")
+	boldRe             = regexp.MustCompile(`\*([^*\n]+)\*`)
+	italicRe           = regexp.MustCompile(`_([^_\n]+)_`)
+	strikeRe           = regexp.MustCompile(`~([^~\n]+)~`)
+	quoteLineRe        = regexp.MustCompile(`(?m)^&gt;\s?(.*)$`)
+	quoteLinePlainRe   = regexp.MustCompile(`(?m)^>\s?(.*)$`)
+)
+
+// RenderBlocks SlackMessage.Blocks を指定フォーマットのテキストに変換する
+// blocks が空の場合は fallbackText (通常は msg.Text) を RenderText にかけた結果を返す
+func RenderBlocks(blocks []interface{}, fallbackText string, format Format, resolver MentionResolver) (string, error) {
+	if len(blocks) == 0 {
+		return RenderText(fallbackText, format, resolver)
+	}
+
+	var parts []string
+	for _, raw := range blocks {
+		block, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		rendered, err := renderBlock(block, format, resolver)
+		if err != nil {
+			return "", err
+		}
+		if rendered != "" {
+			parts = append(parts, rendered)
+		}
+	}
+
+	switch format {
+	case FormatHTML:
+		return strings.Join(parts, "<br/>"), nil
+	default:
+		return strings.Join(parts, "\n"), nil
+	}
+}
+
+// renderBlock 1 つの block (rich_text, section, header, context, divider, image, actions) を変換する
+func renderBlock(block map[string]interface{}, format Format, resolver MentionResolver) (string, error) {
+	blockType, _ := block["type"].(string)
+
+	switch blockType {
+	case "divider":
+		if format == FormatHTML {
+			return "<hr/>", nil
+		}
+		return "---", nil
+
+	case "image":
+		alt, _ := block["alt_text"].(string)
+		imageURL, _ := block["image_url"].(string)
+		if format == FormatHTML {
+			return fmt.Sprintf(`<img src="%s" alt="%s"/>`, html.EscapeString(imageURL), html.EscapeString(alt)), nil
+		}
+		return fmt.Sprintf("![%s](%s)", alt, imageURL), nil
+
+	case "header":
+		text := blockTextField(block["text"])
+		rendered, err := RenderText(text, format, resolver)
+		if err != nil {
+			return "", err
+		}
+		if format == FormatHTML {
+			return fmt.Sprintf("<h2>%s</h2>", rendered), nil
+		}
+		return "## " + rendered, nil
+
+	case "section":
+		text := blockTextField(block["text"])
+		rendered, err := RenderText(text, format, resolver)
+		if err != nil {
+			return "", err
+		}
+		fields, _ := block["fields"].([]interface{})
+		for _, f := range fields {
+			fieldMap, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fieldText, err := RenderText(blockTextField(fieldMap["text"]), format, resolver)
+			if err != nil {
+				return "", err
+			}
+			if rendered != "" {
+				rendered += "\n"
+			}
+			rendered += fieldText
+		}
+		return rendered, nil
+
+	case "context":
+		elements, _ := block["elements"].([]interface{})
+		var elementTexts []string
+		for _, el := range elements {
+			elMap, ok := el.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if elMap["type"] == "image" {
+				continue
+			}
+			rendered, err := RenderText(blockTextField(elMap["text"]), format, resolver)
+			if err != nil {
+				return "", err
+			}
+			elementTexts = append(elementTexts, rendered)
+		}
+		return strings.Join(elementTexts, " | "), nil
+
+	case "rich_text":
+		elements, _ := block["elements"].([]interface{})
+		var lines []string
+		for _, el := range elements {
+			elMap, ok := el.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			line, err := renderRichTextElement(elMap, format, resolver)
+			if err != nil {
+				return "", err
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "actions":
+		// ボタンなどの操作要素はラベルのみをテキストとして残す
+		elements, _ := block["elements"].([]interface{})
+		var labels []string
+		for _, el := range elements {
+			elMap, ok := el.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			label := blockTextField(elMap["text"])
+			if label != "" {
+				labels = append(labels, fmt.Sprintf("[%s]", label))
+			}
+		}
+		return strings.Join(labels, " "), nil
+
+	default:
+		return "", nil
+	}
+}
+
+// renderRichTextElement rich_text 内の section / quote / preformatted / list を変換する
+func renderRichTextElement(el map[string]interface{}, format Format, resolver MentionResolver) (string, error) {
+	elType, _ := el["type"].(string)
+	elements, _ := el["elements"].([]interface{})
+
+	text, err := renderRichTextSpans(elements, format, resolver)
+	if err != nil {
+		return "", err
+	}
+
+	switch elType {
+	case "rich_text_quote":
+		if format == FormatHTML {
+			return fmt.Sprintf("<blockquote>%s</blockquote>", text), nil
+		}
+		return "> " + text, nil
+	case "rich_text_preformatted":
+		if format == FormatHTML {
+			return fmt.Sprintf("<pre><code>%s</code></pre>", html.EscapeString(text)), nil
+		}
+		return "```\n" + text + "\n```", nil
+	case "rich_text_list":
+		// list の elements はスパンではなく rich_text_section なので、1 項目ずつ変換する
+		var items []string
+		for _, raw := range elements {
+			item, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			line, err := renderRichTextElement(item, format, resolver)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, "- "+line)
+		}
+		return strings.Join(items, "\n"), nil
+	default:
+		return text, nil
+	}
+}
+
+// renderRichTextSpans rich_text 要素内の text / link / user / channel / usergroup / emoji スパンを連結する
+func renderRichTextSpans(spans []interface{}, format Format, resolver MentionResolver) (string, error) {
+	var b strings.Builder
+	for _, raw := range spans {
+		span, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		spanType, _ := span["type"].(string)
+
+		switch spanType {
+		case "text":
+			content, _ := span["text"].(string)
+			rendered, err := RenderText(content, format, resolver)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(rendered)
+		case "link":
+			url, _ := span["url"].(string)
+			text, _ := span["text"].(string)
+			if text == "" {
+				text = url
+			}
+			b.WriteString(renderLink(url, text, format))
+		case "user":
+			userID, _ := span["user_id"].(string)
+			name, err := resolveUserName(userID, resolver)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(renderMention(name, format))
+		case "channel":
+			channelID, _ := span["channel_id"].(string)
+			name, err := resolveChannelName(channelID, resolver)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(renderMention("#"+name, format))
+		case "usergroup":
+			usergroupID, _ := span["usergroup_id"].(string)
+			name, err := resolveUsergroupName(usergroupID, resolver)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(renderMention("@"+name, format))
+		case "emoji":
+			name, _ := span["name"].(string)
+			b.WriteString(renderEmoji(name))
+		}
+	}
+	return b.String(), nil
+}
+
+// RenderText プレーンな mrkdwn 文字列 (msg.Text や block の text フィールド) をフォーマット変換する
+//
+// コード片・メンション・リンク・絵文字は先に最終表現へ置き換えた上でプレースホルダに退避し、
+// 残りの地の文にだけ HTML エスケープや強調記法の変換をかけてからプレースホルダを書き戻す。
+// そうしないと、エスケープが置き換え後の <span>/<a> タグ自体を巻き込んでしまったり (HTML が文字参照として表示される)、
+// コードブロックの中身が強調記法の対象になってしまう (*bold* が ``` の中でも展開される) バグを生む
+func RenderText(text string, format Format, resolver MentionResolver) (string, error) {
+	protected := map[string]string{}
+	var order []string
+	protect := func(rendered string) string {
+		key := fmt.Sprintf("\x00PH%d\x00", len(order))
+		order = append(order, key)
+		protected[key] = rendered
+		return key
+	}
+
+	text = codeBlockRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := codeBlockRe.FindStringSubmatch(match)
+		return protect(renderCodeBlock(sub[1], format))
+	})
+	text = inlineCodeRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := inlineCodeRe.FindStringSubmatch(match)
+		return protect(renderInlineCode(sub[1], format))
+	})
+
+	var err error
+	text, err = protectUserMentions(text, resolver, format, protect)
+	if err != nil {
+		return "", err
+	}
+	text, err = protectChannelMentions(text, resolver, format, protect)
+	if err != nil {
+		return "", err
+	}
+	text, err = protectUsergroupMentions(text, resolver, format, protect)
+	if err != nil {
+		return "", err
+	}
+	text = protectLinks(text, format, protect)
+	text = protectEmoji(text, protect)
+
+	switch format {
+	case FormatHTML:
+		text = html.EscapeString(text)
+		text = boldRe.ReplaceAllString(text, "<strong>$1</strong>")
+		text = italicRe.ReplaceAllString(text, "<em>$1</em>")
+		text = strikeRe.ReplaceAllString(text, "<del>$1</del>")
+		text = quoteLineRe.ReplaceAllString(text, "<blockquote>$1</blockquote>")
+		text = strings.ReplaceAll(text, "\n", "<br/>")
+	case FormatMarkdown:
+		text = boldRe.ReplaceAllString(text, "**$1**")
+		text = strikeRe.ReplaceAllString(text, "~~$1~~")
+	default:
+		text = stripMrkdwnPlain(text)
+	}
+
+	for _, key := range order {
+		text = strings.ReplaceAll(text, key, protected[key])
+	}
+
+	return text, nil
+}
+
+// stripMrkdwnPlain プレーンテキスト出力用に *bold*, _italic_, ~strike~, "> quote" の記法を取り除く
+func stripMrkdwnPlain(text string) string {
+	text = boldRe.ReplaceAllString(text, "$1")
+	text = italicRe.ReplaceAllString(text, "$1")
+	text = strikeRe.ReplaceAllString(text, "$1")
+	text = quoteLinePlainRe.ReplaceAllString(text, "$1")
+	return text
+}
+
+// renderCodeBlock ```code block``` の中身をフォーマットごとの最終表現にする。中身自体は強調記法の対象にしない
+func renderCodeBlock(content string, format Format) string {
+	switch format {
+	case FormatHTML:
+		return fmt.Sprintf("<pre><code>%s</code></pre>", html.EscapeString(content))
+	case FormatMarkdown:
+		return "```" + content + "```"
+	default:
+		return content
+	}
+}
+
+// renderInlineCode `code` の中身をフォーマットごとの最終表現にする
+func renderInlineCode(content string, format Format) string {
+	switch format {
+	case FormatHTML:
+		return fmt.Sprintf("<code>%s</code>", html.EscapeString(content))
+	case FormatMarkdown:
+		return "`" + content + "`"
+	default:
+		return content
+	}
+}
+
+func protectUserMentions(text string, resolver MentionResolver, format Format, protect func(string) string) (string, error) {
+	var outerErr error
+	result := userMentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := userMentionRe.FindStringSubmatch(match)
+		userID := sub[1]
+		name, err := resolveUserName(userID, resolver)
+		if err != nil {
+			outerErr = err
+			return match
+		}
+		return protect(renderMention(name, format))
+	})
+	return result, outerErr
+}
+
+func protectChannelMentions(text string, resolver MentionResolver, format Format, protect func(string) string) (string, error) {
+	var outerErr error
+	result := channelMentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := channelMentionRe.FindStringSubmatch(match)
+		channelID := sub[1]
+		label := sub[2]
+		if label != "" {
+			return protect(renderMention("#"+label, format))
+		}
+		name, err := resolveChannelName(channelID, resolver)
+		if err != nil {
+			outerErr = err
+			return match
+		}
+		return protect(renderMention("#"+name, format))
+	})
+	return result, outerErr
+}
+
+func protectUsergroupMentions(text string, resolver MentionResolver, format Format, protect func(string) string) (string, error) {
+	var outerErr error
+	result := usergroupMentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := usergroupMentionRe.FindStringSubmatch(match)
+		usergroupID := sub[1]
+		label := sub[2]
+		if label != "" {
+			return protect(renderMention("@"+label, format))
+		}
+		name, err := resolveUsergroupName(usergroupID, resolver)
+		if err != nil {
+			outerErr = err
+			return match
+		}
+		return protect(renderMention("@"+name, format))
+	})
+	return result, outerErr
+}
+
+func protectLinks(text string, format Format, protect func(string) string) string {
+	return linkRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := linkRe.FindStringSubmatch(match)
+		url := sub[1]
+		label := sub[2]
+		if label == "" {
+			label = url
+		}
+		return protect(renderLink(url, label, format))
+	})
+}
+
+func protectEmoji(text string, protect func(string) string) string {
+	return emojiRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := emojiRe.FindStringSubmatch(match)
+		return protect(renderEmoji(sub[1]))
+	})
+}
+
+func resolveUserName(userID string, resolver MentionResolver) (string, error) {
+	if resolver == nil || userID == "" {
+		return userID, nil
+	}
+	name, err := resolver.UserName(userID)
+	if err != nil || name == "" {
+		return userID, nil
+	}
+	return name, nil
+}
+
+func resolveChannelName(channelID string, resolver MentionResolver) (string, error) {
+	if resolver == nil || channelID == "" {
+		return channelID, nil
+	}
+	name, err := resolver.ChannelName(channelID)
+	if err != nil || name == "" {
+		return channelID, nil
+	}
+	return name, nil
+}
+
+func resolveUsergroupName(usergroupID string, resolver MentionResolver) (string, error) {
+	if resolver == nil || usergroupID == "" {
+		return usergroupID, nil
+	}
+	name, err := resolver.UsergroupName(usergroupID)
+	if err != nil || name == "" {
+		return usergroupID, nil
+	}
+	return name, nil
+}
+
+func renderMention(display string, format Format) string {
+	switch format {
+	case FormatHTML:
+		return fmt.Sprintf(`<span class="mention">%s</span>`, html.EscapeString(display))
+	default:
+		return display
+	}
+}
+
+func renderLink(url, label string, format Format) string {
+	switch format {
+	case FormatHTML:
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(label))
+	case FormatMarkdown:
+		return fmt.Sprintf("[%s](%s)", label, url)
+	default:
+		if label == url {
+			return url
+		}
+		return fmt.Sprintf("%s (%s)", label, url)
+	}
+}
+
+// renderEmoji ショートコードを絵文字テーブルで解決する。見つからない場合は :name: のまま残す
+func renderEmoji(name string) string {
+	if emoji, ok := EmojiTable[name]; ok {
+		return emoji
+	}
+	return ":" + name + ":"
+}
+
+// blockTextField block の "text" フィールド (TextObject 形式 {type, text}) から文字列を取り出す
+func blockTextField(field interface{}) string {
+	switch v := field.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package render
+
+import "testing"
+
+type fakeResolver struct{}
+
+func (fakeResolver) UserName(userID string) (string, error)           { return "Alice", nil }
+func (fakeResolver) ChannelName(channelID string) (string, error)     { return "general", nil }
+func (fakeResolver) UsergroupName(usergroupID string) (string, error) { return "eng", nil }
+
+func TestRenderTextMentionNotReescaped(t *testing.T) {
+	got, err := RenderText("Hello <@U123>", FormatHTML, fakeResolver{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `Hello <span class="mention">Alice</span>`
+	if got != want {
+		t.Errorf("RenderText() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTextLinkNotReescaped(t *testing.T) {
+	got, err := RenderText("Check <https://example.com|here>", FormatHTML, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `Check <a href="https://example.com">here</a>`
+	if got != want {
+		t.Errorf("RenderText() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTextPlainStripsMrkdwn(t *testing.T) {
+	got, err := RenderText("*bold* _italic_ ~strike~", FormatPlain, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "bold italic strike"
+	if got != want {
+		t.Errorf("RenderText() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTextCodeBlockNotEmphasized(t *testing.T) {
+	got, err := RenderText("```let x = *bold*```", FormatHTML, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "<pre><code>let x = *bold*</code></pre>"
+	if got != want {
+		t.Errorf("RenderText() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTextInlineCodeNotEmphasized(t *testing.T) {
+	got, err := RenderText("`*not bold*`", FormatHTML, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "<code>*not bold*</code>"
+	if got != want {
+		t.Errorf("RenderText() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTextMarkdownEmphasis(t *testing.T) {
+	got, err := RenderText("*bold* ~strike~", FormatMarkdown, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "**bold** ~~strike~~"
+	if got != want {
+		t.Errorf("RenderText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package slackclient
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// cacheEntry キャッシュ 1 件分の値と有効期限
+type cacheEntry struct {
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// diskCache sync.RWMutex で保護された TTL 付きキャッシュ。プロセス終了後も再利用できるよう JSON ファイルに永続化する
+type diskCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	path    string
+	ttl     time.Duration
+}
+
+// newDiskCache path が空の場合はディスクへの読み書きを行わないメモリのみのキャッシュになる
+func newDiskCache(path string, ttl time.Duration) *diskCache {
+	c := &diskCache{
+		entries: make(map[string]cacheEntry),
+		path:    path,
+		ttl:     ttl,
+	}
+	c.load()
+	return c
+}
+
+// load path からキャッシュを読み込む。ファイルが無い/壊れている場合は空のキャッシュから始める
+func (c *diskCache) load() {
+	if c.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+
+	var entries map[string]cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = entries
+}
+
+// persist 現在のキャッシュ内容を path に書き出す。path が未設定の場合は何もしない
+func (c *diskCache) persist() {
+	if c.path == "" {
+		return
+	}
+
+	c.mu.RLock()
+	data, err := json.Marshal(c.entries)
+	c.mu.RUnlock()
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(c.path, data, 0o600)
+}
+
+// get 有効期限内であればキャッシュされた値を返す
+func (c *diskCache) get(key string) (string, bool) {
+	c.mu.RLock()
+	entry, exists := c.entries[key]
+	c.mu.RUnlock()
+
+	if !exists || time.Now().After(entry.ExpiresAt) {
+		return "", false
+	}
+	return entry.Value, true
+}
+
+// set 値をキャッシュに書き込み、非同期でディスクに反映する
+func (c *diskCache) set(key, value string) {
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{Value: value, ExpiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	go c.persist()
+}
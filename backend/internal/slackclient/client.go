@@ -0,0 +1,276 @@
+// Package slackclient は Slack Web API 呼び出しを並行安全かつレート制限に配慮した形でラップする。
+// users.info / conversations.info / usergroups.list のような lookup 系 API を対象に、
+// singleflight による重複排除、TTL 付きディスクキャッシュ、トークンバケットでの rate limit 遵守を提供する。
+package slackclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultCacheTTL lookup 結果をキャッシュしておく期間。ユーザー名などは滅多に変わらないため長めに取る
+const defaultCacheTTL = 24 * time.Hour
+
+// maxResolveWorkers 1 スレッド内のユーザー名解決で同時に投げる Slack API リクエストの上限
+const maxResolveWorkers = 8
+
+// Client Slack Web API 呼び出しをまとめて管理するクライアント
+type Client struct {
+	httpClient *http.Client
+	token      string
+
+	group    singleflight.Group
+	limiters *rateLimiterGroup
+
+	userCache      *diskCache
+	channelCache   *diskCache
+	usergroupCache *diskCache
+}
+
+// NewClient token は Bot User OAuth Token。cacheDir が空文字列の場合はディスク永続化を行わない
+func NewClient(token, cacheDir string) *Client {
+	userCachePath, channelCachePath, usergroupCachePath := "", "", ""
+	if cacheDir != "" {
+		userCachePath = cacheDir + "/users.json"
+		channelCachePath = cacheDir + "/channels.json"
+		usergroupCachePath = cacheDir + "/usergroups.json"
+	}
+
+	return &Client{
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		token:          token,
+		limiters:       newRateLimiterGroup(),
+		userCache:      newDiskCache(userCachePath, defaultCacheTTL),
+		channelCache:   newDiskCache(channelCachePath, defaultCacheTTL),
+		usergroupCache: newDiskCache(usergroupCachePath, defaultCacheTTL),
+	}
+}
+
+// UserName users.info からユーザーの表示名を解決する。同時に同じ userID が要求された場合は 1 回の API 呼び出しに集約される
+func (c *Client) UserName(ctx context.Context, userID string) (string, error) {
+	if name, ok := c.userCache.get(userID); ok {
+		return name, nil
+	}
+
+	value, err, _ := c.group.Do("users.info:"+userID, func() (interface{}, error) {
+		var response struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+			User  struct {
+				Profile struct {
+					RealName string `json:"real_name"`
+				} `json:"profile"`
+			} `json:"user"`
+		}
+
+		data := url.Values{}
+		data.Set("user", userID)
+		if err := c.call(ctx, "users.info", data, &response); err != nil {
+			return "", err
+		}
+		if !response.OK {
+			return "", fmt.Errorf("slack api returned an error: %s", response.Error)
+		}
+
+		c.userCache.set(userID, response.User.Profile.RealName)
+		return response.User.Profile.RealName, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// ResolveUsers userIDs に含まれるユニークな ID を束ねた worker pool で並行解決する
+// スレッド 1 件分のメッセージをまとめて処理する handleFetchMessage から呼ばれることを想定している
+func (c *Client) ResolveUsers(ctx context.Context, userIDs []string) map[string]string {
+	unique := make(map[string]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		if id != "" {
+			unique[id] = struct{}{}
+		}
+	}
+
+	type result struct {
+		id   string
+		name string
+	}
+
+	jobs := make(chan string)
+	results := make(chan result, len(unique))
+
+	workerCount := maxResolveWorkers
+	if workerCount > len(unique) {
+		workerCount = len(unique)
+	}
+	if workerCount == 0 {
+		return map[string]string{}
+	}
+
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			for id := range jobs {
+				name, err := c.UserName(ctx, id)
+				if err != nil {
+					name = "Unknown"
+				}
+				results <- result{id: id, name: name}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for id := range unique {
+			jobs <- id
+		}
+	}()
+
+	resolved := make(map[string]string, len(unique))
+	for range unique {
+		r := <-results
+		resolved[r.id] = r.name
+	}
+	return resolved
+}
+
+// ChannelName conversations.info からチャンネル名を解決する
+func (c *Client) ChannelName(ctx context.Context, channelID string) (string, error) {
+	if name, ok := c.channelCache.get(channelID); ok {
+		return name, nil
+	}
+
+	value, err, _ := c.group.Do("conversations.info:"+channelID, func() (interface{}, error) {
+		var response struct {
+			OK      bool   `json:"ok"`
+			Error   string `json:"error"`
+			Channel struct {
+				Name string `json:"name"`
+			} `json:"channel"`
+		}
+
+		data := url.Values{}
+		data.Set("channel", channelID)
+		if err := c.call(ctx, "conversations.info", data, &response); err != nil {
+			return "", err
+		}
+		if !response.OK {
+			return "", fmt.Errorf("slack api returned an error: %s", response.Error)
+		}
+
+		c.channelCache.set(channelID, response.Channel.Name)
+		return response.Channel.Name, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// UsergroupName usergroups.list は一覧 API なので、初回呼び出し時に全件をキャッシュへ取り込む
+func (c *Client) UsergroupName(ctx context.Context, usergroupID string) (string, error) {
+	if name, ok := c.usergroupCache.get(usergroupID); ok {
+		return name, nil
+	}
+
+	_, err, _ := c.group.Do("usergroups.list", func() (interface{}, error) {
+		var response struct {
+			OK         bool   `json:"ok"`
+			Error      string `json:"error"`
+			Usergroups []struct {
+				ID     string `json:"id"`
+				Handle string `json:"handle"`
+			} `json:"usergroups"`
+		}
+
+		if err := c.call(ctx, "usergroups.list", url.Values{}, &response); err != nil {
+			return nil, err
+		}
+		if !response.OK {
+			return nil, fmt.Errorf("slack api returned an error: %s", response.Error)
+		}
+
+		for _, group := range response.Usergroups {
+			c.usergroupCache.set(group.ID, group.Handle)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := c.usergroupCache.get(usergroupID)
+	if !ok {
+		return "", fmt.Errorf("usergroup not found: %s", usergroupID)
+	}
+	return name, nil
+}
+
+// call Slack Web API の GET メソッドを呼び出す。メソッドごとのトークンバケットで待ち合わせ、
+// 429 は Retry-After 分、通信エラーと 200 以外のステータスは指数バックオフ + ジッタ分待って、合計 3 回まで試行する。
+// レスポンスのデコード失敗は再試行せずにそのまま返す
+func (c *Client) call(ctx context.Context, method string, data url.Values, out interface{}) error {
+	limiter := c.limiters.forMethod(method)
+	apiURL := "https://slack.com/api/" + method
+
+	const maxAttempts = 3
+	var lastErr error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		limiter.wait()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.URL.RawQuery = data.Encode()
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to call slack api: %w", err)
+			time.Sleep(backoffWithJitter(attempt))
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			limiter.penalize(retryAfter)
+			lastErr = fmt.Errorf("slack api rate limited method=%s", method)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("slack api request failed with status: %s", resp.Status)
+			time.Sleep(backoffWithJitter(attempt))
+			continue
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(out)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode slack api response: %w", err)
+		}
+		return nil
+	}
+
+	return lastErr
+}
+
+// parseRetryAfter Slack の 429 に付与される Retry-After (秒) をパースする。解釈できない場合は 1 秒にフォールバックする
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
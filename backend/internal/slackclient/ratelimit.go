@@ -0,0 +1,95 @@
+package slackclient
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// methodLimiter Slack API の 1 メソッドあたりのトークンバケット型レートリミッタ
+// Tier-3/Tier-4 の目安である毎秒数リクエスト程度を想定し、メソッドごとに goroutine 間で共有する
+type methodLimiter struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	refillRate float64 // tokens per second
+	lastRefill time.Time
+}
+
+func newMethodLimiter(ratePerSecond float64) *methodLimiter {
+	return &methodLimiter{
+		tokens:     ratePerSecond,
+		maxTokens:  ratePerSecond,
+		refillRate: ratePerSecond,
+		lastRefill: time.Now(),
+	}
+}
+
+// wait トークンが補充されるまでブロックしてから 1 トークン消費する
+func (l *methodLimiter) wait() {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		elapsed := now.Sub(l.lastRefill).Seconds()
+		l.tokens = minFloat(l.maxTokens, l.tokens+elapsed*l.refillRate)
+		l.lastRefill = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return
+		}
+
+		wait := time.Duration((1 - l.tokens) / l.refillRate * float64(time.Second))
+		l.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+// penalize 429 を受け取った際に一時的にトークンを使い果たす。次の wait() が Retry-After 分待つことになる
+func (l *methodLimiter) penalize(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = 0
+	l.lastRefill = time.Now().Add(retryAfter)
+}
+
+// rateLimiterGroup メソッド名ごとに methodLimiter を使い分けるレジストリ
+type rateLimiterGroup struct {
+	mu       sync.Mutex
+	limiters map[string]*methodLimiter
+}
+
+func newRateLimiterGroup() *rateLimiterGroup {
+	return &rateLimiterGroup{limiters: make(map[string]*methodLimiter)}
+}
+
+func (g *rateLimiterGroup) forMethod(method string) *methodLimiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if l, ok := g.limiters[method]; ok {
+		return l
+	}
+	// Slack の Tier-3 相当 (概ね 50+ req/min) を安全側に倒したデフォルト値
+	l := newMethodLimiter(1)
+	g.limiters[method] = l
+	return l
+}
+
+// backoffWithJitter 429 以外の一時的な失敗に対する指数バックオフ + ジッタを計算する
+func backoffWithJitter(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt)) * 200 * time.Millisecond
+	if base > 10*time.Second {
+		base = 10 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base) / 2))
+	return base + jitter
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,65 @@
+// Package slackref は Slack のメッセージ permalink をパースし、
+// conversations.replies / conversations.history を呼び出すために必要な情報に変換する。
+package slackref
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// SlackRef permalink から抽出した参照情報
+type SlackRef struct {
+	Workspace string // サブドメイン (ワークスペース名)、enterprise grid では "enterprise"
+	TeamID    string // enterprise grid の /archives/T.../C... に含まれるチーム ID。無い場合は空文字列
+	ChannelID string // C (public), G (private/MPIM), D (DM) のいずれかで始まるチャンネル ID
+	Ts        string // permalink 中の p... から復元したメッセージの ts
+	ThreadTs  string // ?thread_ts= クエリパラメータ。無い場合は空文字列
+	Cid       string // ?cid= クエリパラメータ (クロスワークスペース共有リンクで使われるチャンネル ID)。無い場合は空文字列
+}
+
+// IsDM ChannelID が DM (D から始まる) かどうかを返す。
+// MPIM は G から始まり private チャンネルと ID だけでは区別できないため、MPIM に対しては false を返す
+func (r *SlackRef) IsDM() bool {
+	return len(r.ChannelID) > 0 && r.ChannelID[0] == 'D'
+}
+
+// HasDistinctThread ThreadTs が設定されていて、かつ permalink 自体が指す ts と異なるかどうかを返す
+// true の場合、permalink は親スレッドの中の特定の返信を指している
+func (r *SlackRef) HasDistinctThread() bool {
+	return r.ThreadTs != "" && r.ThreadTs != r.Ts
+}
+
+// permalinkPathRe /archives/(T.../)?(C|G|D).../p1234567890123456 部分にマッチする
+var permalinkPathRe = regexp.MustCompile(`^/archives/(?:(T[A-Za-z0-9]+)/)?([CGD][A-Za-z0-9]+)/p(\d{10})(\d{6})$`)
+
+// ParseSlackLink Slack permalink をパースして SlackRef を返す。
+// public/private チャンネル、DM、MPIM、enterprise grid (enterprise.slack.com/archives/T.../C...) に対応する
+func ParseSlackLink(link string) (*SlackRef, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	host := parsed.Hostname()
+	const suffix = ".slack.com"
+	if len(host) <= len(suffix) || host[len(host)-len(suffix):] != suffix {
+		return nil, fmt.Errorf("not a slack.com url: %s", link)
+	}
+	workspace := host[:len(host)-len(suffix)]
+
+	match := permalinkPathRe.FindStringSubmatch(parsed.Path)
+	if match == nil {
+		return nil, fmt.Errorf("unrecognized slack permalink path: %s", parsed.Path)
+	}
+
+	ref := &SlackRef{
+		Workspace: workspace,
+		TeamID:    match[1],
+		ChannelID: match[2],
+		Ts:        fmt.Sprintf("%s.%s", match[3], match[4]),
+		ThreadTs:  parsed.Query().Get("thread_ts"),
+		Cid:       parsed.Query().Get("cid"),
+	}
+	return ref, nil
+}
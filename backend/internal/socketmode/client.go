@@ -0,0 +1,263 @@
+// Package socketmode は Slack の Socket Mode (apps.connections.open + WebSocket) に接続し、
+// message / reaction_added イベントを channel:thread_ts 単位の Index に配信する常駐サブシステム。
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pingInterval Socket Mode 接続の生存確認のために ping を送る間隔
+const pingInterval = 30 * time.Second
+
+// maxBackoff 再接続時の指数バックオフの上限
+const maxBackoff = 30 * time.Second
+
+// Client Slack Socket Mode への接続を維持し、受信したイベントを Index に流し込む
+type Client struct {
+	appToken   string
+	botToken   string
+	index      *Index
+	httpClient *http.Client
+	connected  atomic.Bool
+}
+
+// NewClient appToken は xapp- で始まる App-Level Token、botToken は chat.postMessage 等で使う Bot Token
+func NewClient(appToken, botToken string, index *Index) *Client {
+	return &Client{
+		appToken:   appToken,
+		botToken:   botToken,
+		index:      index,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Connected 現在 Socket Mode 接続が生きているかどうかを返す。GET /api/thread/stream の REST フォールバック判定に使う
+func (c *Client) Connected() bool {
+	return c.connected.Load()
+}
+
+// Run ctx がキャンセルされるまで接続・再接続を繰り返す。呼び出し元は goroutine で起動すること
+func (c *Client) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := c.connectAndServe(ctx)
+		c.connected.Store(false)
+		if err != nil {
+			log.Printf("socket mode connection lost: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connectAndServe apps.connections.open で wss URL を取得し、イベントを読み続ける
+func (c *Client) connectAndServe(ctx context.Context) error {
+	wsURL, err := c.openConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open socket mode connection: %w", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial socket mode websocket: %w", err)
+	}
+	defer conn.Close()
+
+	c.connected.Store(true)
+
+	stopPing := make(chan struct{})
+	defer close(stopPing)
+	go c.keepAlive(conn, stopPing)
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("socket mode read failed: %w", err)
+		}
+		c.handleEnvelope(conn, data)
+	}
+}
+
+// keepAlive pingInterval ごとに WebSocket の ping フレームを送信する
+func (c *Client) keepAlive(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// openConnection apps.connections.open を呼び出し、接続先の wss URL を取得する
+func (c *Client) openConnection(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://slack.com/api/apps.connections.open", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.appToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call slack api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("slack api request failed with status: %s", resp.Status)
+	}
+
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		URL   string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode slack api response: %w", err)
+	}
+	if !response.OK {
+		return "", fmt.Errorf("slack api returned an error: %s", response.Error)
+	}
+	return response.URL, nil
+}
+
+// envelope Socket Mode 上を流れるメッセージの共通エンベロープ
+type envelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// eventsAPIPayload events_api エンベロープの payload.event に入っている差分イベント
+type eventsAPIPayload struct {
+	Event struct {
+		Type      string `json:"type"`
+		Subtype   string `json:"subtype"`
+		Channel   string `json:"channel"`
+		User      string `json:"user"`
+		Text      string `json:"text"`
+		Ts        string `json:"ts"`
+		ThreadTs  string `json:"thread_ts"`
+		DeletedTs string `json:"deleted_ts"`
+		Reaction  string `json:"reaction"`
+		Message   struct {
+			Ts   string `json:"ts"`
+			User string `json:"user"`
+			Text string `json:"text"`
+		} `json:"message"`
+		Item struct {
+			Channel string `json:"channel"`
+			Ts      string `json:"ts"`
+		} `json:"item"`
+	} `json:"event"`
+}
+
+// handleEnvelope 受信した 1 フレームを ack し、events_api メッセージであれば Index に配信する
+func (c *Client) handleEnvelope(conn *websocket.Conn, data []byte) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return
+	}
+
+	// Socket Mode は envelope_id に対する ack を期待しており、一定時間内に返さないと再送されてしまう
+	if env.EnvelopeID != "" {
+		ack, _ := json.Marshal(map[string]string{"envelope_id": env.EnvelopeID})
+		_ = conn.WriteMessage(websocket.TextMessage, ack)
+	}
+
+	if env.Type != "events_api" {
+		return
+	}
+
+	var payload eventsAPIPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		return
+	}
+
+	switch payload.Event.Type {
+	case "message":
+		// thread_ts が空の場合のフォールバック先は subtype によって異なる。message_changed / message_deleted では
+		// 外側の ts は編集・削除の通知自体の ts であって、編集・削除されたメッセージ自身の ts ではないため、
+		// それぞれ message.ts / deleted_ts をフォールバックに使う必要がある (でないと非スレッドの投稿を編集・削除した際に
+		// 購読者が誰も登録していない channel:<通知の ts> 宛てに配信してしまう)
+		switch payload.Event.Subtype {
+		case "message_deleted":
+			threadTs := payload.Event.ThreadTs
+			if threadTs == "" {
+				threadTs = payload.Event.DeletedTs
+			}
+			key := payload.Event.Channel + ":" + threadTs
+			c.index.RememberThread(payload.Event.Channel, payload.Event.DeletedTs, threadTs)
+			c.index.Publish(key, Event{
+				Type: "message_deleted", Channel: payload.Event.Channel, ThreadTs: threadTs,
+				Message: &Message{Ts: payload.Event.DeletedTs},
+			})
+		case "message_changed":
+			threadTs := payload.Event.ThreadTs
+			if threadTs == "" {
+				threadTs = payload.Event.Message.Ts
+			}
+			key := payload.Event.Channel + ":" + threadTs
+			c.index.RememberThread(payload.Event.Channel, payload.Event.Message.Ts, threadTs)
+			c.index.Publish(key, Event{
+				Type: "message_edited", Channel: payload.Event.Channel, ThreadTs: threadTs,
+				Message: &Message{Ts: payload.Event.Message.Ts, User: payload.Event.Message.User, Text: payload.Event.Message.Text},
+			})
+		default:
+			threadTs := payload.Event.ThreadTs
+			if threadTs == "" {
+				threadTs = payload.Event.Ts
+			}
+			key := payload.Event.Channel + ":" + threadTs
+			c.index.RememberThread(payload.Event.Channel, payload.Event.Ts, threadTs)
+			c.index.Publish(key, Event{
+				Type: "message_appended", Channel: payload.Event.Channel, ThreadTs: threadTs,
+				Message: &Message{Ts: payload.Event.Ts, User: payload.Event.User, Text: payload.Event.Text},
+			})
+		}
+
+	case "reaction_added":
+		// reaction_added の payload には thread_ts が含まれず item.ts (リアクション対象メッセージ自身の ts) しか
+		// 分からないため、observed な message イベントから記録しておいた対応を使ってスレッドの thread_ts に変換する。
+		// 未観測 (再起動直後など) の場合は item.ts をそのまま使う (スレッド親へのリアクションであれば一致する)
+		threadTs, ok := c.index.ThreadTsFor(payload.Event.Item.Channel, payload.Event.Item.Ts)
+		if !ok {
+			threadTs = payload.Event.Item.Ts
+		}
+		key := payload.Event.Item.Channel + ":" + threadTs
+		c.index.Publish(key, Event{
+			Type: "reaction_added", Channel: payload.Event.Item.Channel, ThreadTs: threadTs,
+			Reaction: &ReactionDelta{Ts: payload.Event.Item.Ts, Emoji: payload.Event.Reaction, UserID: payload.Event.User},
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package socketmode
+
+import "sync"
+
+// Event Socket Mode 経由で受け取ったスレッドの差分。channel:thread_ts をキーに配信される
+type Event struct {
+	Type     string         `json:"type"` // message_appended | message_edited | message_deleted | reaction_added
+	Channel  string         `json:"channel"`
+	ThreadTs string         `json:"thread_ts"`
+	Message  *Message       `json:"message,omitempty"`
+	Reaction *ReactionDelta `json:"reaction,omitempty"`
+}
+
+// Message message_appended / message_edited / message_deleted が運ぶメッセージ本体 (削除時は Ts のみ)
+type Message struct {
+	Ts   string `json:"ts"`
+	User string `json:"user,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// ReactionDelta reaction_added イベントが運ぶリアクションの増分
+type ReactionDelta struct {
+	Ts     string `json:"ts"`
+	Emoji  string `json:"emoji"`
+	UserID string `json:"user_id"`
+}
+
+// maxRememberedMessages threadTsByMessage が際限なく肥大化しないようにする簡易的な上限。
+// 超えたら古いものから個別に間引く代わりに丸ごとクリアする (プロセス生存中の素朴なキャッシュなので厳密さは求めない)
+const maxRememberedMessages = 10000
+
+// Index channel:thread_ts ごとの購読者 (GET /api/thread/stream の各コネクション) を管理する in-memory インデックス
+type Index struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan Event
+
+	// threadTsByMessage "channel:ts" (メッセージ自身の ts) -> そのメッセージが属するスレッドの thread_ts。
+	// reaction_added イベントには thread_ts が含まれず item.ts (リアクションが付いたメッセージ自身の ts) しか
+	// 分からないため、observed な message イベントから対応を覚えておき、reaction を正しい購読キーに変換する
+	threadTsByMessage map[string]string
+}
+
+// NewIndex 空の Index を生成する
+func NewIndex() *Index {
+	return &Index{
+		subscribers:       make(map[string][]chan Event),
+		threadTsByMessage: make(map[string]string),
+	}
+}
+
+// RememberThread channel 内の ts が threadTs のスレッドに属することを記録する
+func (idx *Index) RememberThread(channel, ts, threadTs string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if len(idx.threadTsByMessage) >= maxRememberedMessages {
+		idx.threadTsByMessage = make(map[string]string)
+	}
+	idx.threadTsByMessage[channel+":"+ts] = threadTs
+}
+
+// ThreadTsFor RememberThread で記録された ts -> thread_ts の対応を引く。未観測の場合は ok が false になる
+func (idx *Index) ThreadTsFor(channel, ts string) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	threadTs, ok := idx.threadTsByMessage[channel+":"+ts]
+	return threadTs, ok
+}
+
+// Subscribe key (channel:thread_ts) 宛てのイベントを受け取るチャンネルを登録する。
+// 戻り値の unsubscribe は呼び出し側が接続を閉じる際に必ず呼ぶこと
+func (idx *Index) Subscribe(key string) (ch chan Event, unsubscribe func()) {
+	ch = make(chan Event, 16)
+
+	idx.mu.Lock()
+	idx.subscribers[key] = append(idx.subscribers[key], ch)
+	idx.mu.Unlock()
+
+	unsubscribe = func() {
+		idx.mu.Lock()
+		defer idx.mu.Unlock()
+		subs := idx.subscribers[key]
+		for i, existing := range subs {
+			if existing == ch {
+				idx.subscribers[key] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}
+	return ch, unsubscribe
+}
+
+// Publish key を購読している全コネクションに event を配信する。受信側が詰まっていても他の購読者はブロックしない
+func (idx *Index) Publish(key string, event Event) {
+	idx.mu.Lock()
+	subs := append([]chan Event(nil), idx.subscribers[key]...)
+	idx.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- event:
+		default:
+			// バッファが詰まっている購読者はスキップする (古いイベントより接続の生存を優先)
+		}
+	}
+}
@@ -11,9 +11,18 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/export"
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/llm"
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/render"
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/slackclient"
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/slackref"
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/socketmode"
+	"github.com/Haruki1090/SlackApp_LinkWise/backend/internal/summarize"
 )
 
 // SlackMessageResponse Slack APIの conversations.replies メソッドのレスポンスを格納する構造体
@@ -29,16 +38,34 @@ type SlackMessageResponse struct {
 
 // SlackMessage 個々のメッセージの情報を格納する構造体
 type SlackMessage struct {
-	ClientMsgID  string        `json:"client_msg_id"`  // クライアントが生成したメッセージ ID
-	Type         string        `json:"type"`           // メッセージの種類
-	Subtype      string        `json:"subtype"`        // メッセージのサブタイプ
-	Text         string        `json:"text"`           // メッセージの本文
-	User         string        `json:"user"`           // メッセージを投稿したユーザーの ID
-	Ts           string        `json:"ts"`             // メッセージのタイムスタンプ
-	ThreadTs     *string       `json:"thread_ts"`      // スレッドの親メッセージのタイムスタンプ。スレッドにない場合は nil
-	ParentUserID *string       `json:"parent_user_id"` // 親メッセージを投稿したユーザーの ID。親メッセージがない場合は nil
-	Team         string        `json:"team"`           // メッセージを投稿したチームの ID
-	Blocks       []interface{} `json:"blocks"`         // メッセージのブロック
+	ClientMsgID  string          `json:"client_msg_id"`  // クライアントが生成したメッセージ ID
+	Type         string          `json:"type"`           // メッセージの種類
+	Subtype      string          `json:"subtype"`        // メッセージのサブタイプ
+	Text         string          `json:"text"`           // メッセージの本文
+	User         string          `json:"user"`           // メッセージを投稿したユーザーの ID
+	Ts           string          `json:"ts"`             // メッセージのタイムスタンプ
+	ThreadTs     *string         `json:"thread_ts"`      // スレッドの親メッセージのタイムスタンプ。スレッドにない場合は nil
+	ParentUserID *string         `json:"parent_user_id"` // 親メッセージを投稿したユーザーの ID。親メッセージがない場合は nil
+	Team         string          `json:"team"`           // メッセージを投稿したチームの ID
+	Blocks       []interface{}   `json:"blocks"`         // メッセージのブロック
+	Files        []SlackFile     `json:"files"`          // 添付ファイル
+	Reactions    []SlackReaction `json:"reactions"`      // リアクション
+}
+
+// SlackFile メッセージに添付されたファイルの情報
+type SlackFile struct {
+	ID         string `json:"id"`          // ファイル ID (files.info で中身を取得する際のキー)
+	Name       string `json:"name"`        // ファイル名
+	Mimetype   string `json:"mimetype"`    // MIME タイプ
+	Size       int64  `json:"size"`        // バイト数
+	URLPrivate string `json:"url_private"` // Bot トークンでの認可が必要なダウンロード URL
+}
+
+// SlackReaction メッセージに付いたリアクションの集計
+type SlackReaction struct {
+	Name  string   `json:"name"`  // 絵文字のショートコード (コロン抜き)
+	Count int      `json:"count"` // リアクションした人数
+	Users []string `json:"users"` // リアクションしたユーザー ID
 }
 
 // RequestPayload フロントエンドから受け取るリクエストの構造体
@@ -57,13 +84,24 @@ type ResponsePayload struct {
 
 // ResponseData 個々のメッセージをフロントエンドに返すための構造体
 type ResponseData struct {
-	Timestamp string `json:"timestamp"`
-	UserName  string `json:"user_name"`
-	Text      string `json:"text"`
+	Timestamp   string                   `json:"timestamp"`
+	UserName    string                   `json:"user_name"`
+	Text        string                   `json:"text"`
+	Highlighted bool                     `json:"highlighted,omitempty"` // permalink が thread_ts 付きで特定の返信を指している場合に true
+	Reactions   []export.ReactionSummary `json:"reactions,omitempty"`   // 絵文字ごとに集計したリアクション
 }
 
 var slackBotToken string
 
+// slackAPI users.info / conversations.info / usergroups.list の lookup をまとめて扱うクライアント
+var slackAPI *slackclient.Client
+
+// threadIndex Socket Mode が受信したスレッドの差分を channel:thread_ts ごとに保持するインデックス
+var threadIndex = socketmode.NewIndex()
+
+// socketModeClient SLACK_APP_TOKEN が設定されている場合のみ起動する Socket Mode 接続。未起動なら nil のまま
+var socketModeClient *socketmode.Client
+
 func main() {
 	// .env ファイルから環境変数を読み込む
 	err := godotenv.Load()
@@ -76,6 +114,17 @@ func main() {
 		log.Fatal("Error: SLACK_BOT_TOKEN environment variable is not set")
 	}
 
+	// lookup キャッシュの永続化先。未設定なら同一プロセス内のメモリキャッシュのみで動作する
+	cacheDir := os.Getenv("SLACK_CACHE_DIR")
+	slackAPI = slackclient.NewClient(slackBotToken, cacheDir)
+
+	// SLACK_APP_TOKEN (xapp- で始まる App-Level Token) が設定されている場合のみ Socket Mode を起動する。
+	// 未設定なら GET /api/thread/stream はリアルタイム更新なしの REST フォールバックとして動く
+	if appToken := os.Getenv("SLACK_APP_TOKEN"); appToken != "" {
+		socketModeClient = socketmode.NewClient(appToken, slackBotToken, threadIndex)
+		go socketModeClient.Run(context.Background())
+	}
+
 	// Render の環境変数 PORT を取得
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -84,6 +133,9 @@ func main() {
 
 	// HTTPハンドラーの設定
 	http.HandleFunc("/api/fetch-message", handleFetchMessage)
+	http.HandleFunc("/api/summarize-thread", handleSummarizeThread)
+	http.HandleFunc("/api/thread/stream", handleThreadStream)
+	http.HandleFunc("/api/export", handleExportThread)
 
 	fmt.Printf("Go backend running on http://localhost:%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
@@ -110,15 +162,18 @@ func handleFetchMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Slack URLの形式を検証し、チャンネルIDとタイムスタンプを抽出
-	channelID, timestamp := extractSlackLinkInfo(slackURL)
-	if channelID == "" || timestamp == "" {
+	// Slack permalink をパースし、ワークスペース・チャンネル ID・ts・thread_ts を抽出する
+	ref, err := slackref.ParseSlackLink(slackURL)
+	if err != nil {
 		http.Error(w, "Invalid Slack message URL format", http.StatusBadRequest)
 		return
 	}
 
-	// スレッド内のメッセージを取得
-	messages, err := getThreadMessages(channelID, timestamp)
+	// ?format=html|markdown|plain でフロントエンドへの返却形式を選択する。未指定/不正値は plain
+	format := render.ParseFormat(r.URL.Query().Get("format"))
+
+	// DM (D で始まるチャンネル) への単発メッセージリンクは conversations.replies ではなく conversations.history で取得する
+	messages, err := fetchMessagesForRef(ref)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error getting messages: %v", err), http.StatusInternalServerError)
 		return
@@ -129,12 +184,20 @@ func handleFetchMessage(w http.ResponseWriter, r *http.Request) {
 		return messages[i].Ts < messages[j].Ts
 	})
 
+	resolver := mentionResolver{}
+
+	// スレッド内のユニークなユーザー ID をまとめて並行解決し、シリアルな users.info 呼び出しを避ける
+	userIDs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		userIDs = append(userIDs, msg.User)
+	}
+	userNames := slackAPI.ResolveUsers(r.Context(), userIDs)
+
 	// レスポンス用にメッセージを整形
 	var responseMessages []ResponseData
 	for _, msg := range messages {
-		// ユーザー名を取得
-		userName, err := getUserName(msg.User)
-		if err != nil {
+		userName := userNames[msg.User]
+		if userName == "" {
 			userName = "Unknown"
 		}
 
@@ -144,10 +207,19 @@ func handleFetchMessage(w http.ResponseWriter, r *http.Request) {
 			formattedTimestamp = msg.Ts
 		}
 
+		// mrkdwn/block kit を指定フォーマットに変換する。Blocks が無ければ Text をそのまま変換する
+		text, err := render.RenderBlocks(msg.Blocks, msg.Text, format, resolver)
+		if err != nil {
+			text = msg.Text
+		}
+
 		responseMessages = append(responseMessages, ResponseData{
 			Timestamp: formattedTimestamp,
 			UserName:  userName,
-			Text:      msg.Text,
+			Text:      text,
+			// thread_ts 付きリンクで親スレッド全体を取得した場合、permalink が指していた返信だけ印を付ける
+			Highlighted: ref.HasDistinctThread() && msg.Ts == ref.Ts,
+			Reactions:   resolveReactions(msg.Reactions, resolver),
 		})
 	}
 
@@ -158,56 +230,380 @@ func handleFetchMessage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// userCache ユーザー名のキャッシュを保持するマップ
-var userCache = make(map[string]string)
+// fetchMessagesForRef SlackRef の種類に応じて conversations.replies / conversations.history を使い分ける
+func fetchMessagesForRef(ref *slackref.SlackRef) ([]SlackMessage, error) {
+	// permalink が thread_ts を伴う場合は、返信ではなく親スレッドの ts を起点に全件取得する
+	parentTs := ref.Ts
+	if ref.HasDistinctThread() {
+		parentTs = ref.ThreadTs
+	}
 
-// getUserName Slack API を使用してユーザー名を取得する関数
-func getUserName(userID string) (string, error) {
-	// キャッシュを確認
-	if name, exists := userCache[userID]; exists {
-		return name, nil
+	if ref.IsDM() && !ref.HasDistinctThread() {
+		// DM の単発メッセージはスレッドの親ではないことが多く、conversations.replies が 1 件しか返さないため
+		// conversations.history から該当 ts のみを取得する
+		return getStandaloneMessage(ref.ChannelID, ref.Ts)
 	}
-	apiURL := "https://slack.com/api/users.info"
-	client := &http.Client{}
-	data := url.Values{}
-	data.Set("user", userID)
 
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	return getThreadMessages(ref.ChannelID, parentTs)
+}
+
+// handleSummarizeThread スレッドを取得し、LLM に要約させた結果を Server-Sent Events でストリーミングするハンドラー
+// ?post_back=true が指定された場合、要約の送信が完了したら chat.postMessage でスレッドに返信する
+func handleSummarizeThread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqPayload RequestPayload
+	if err := json.NewDecoder(r.Body).Decode(&reqPayload); err != nil {
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+
+	ref, err := slackref.ParseSlackLink(reqPayload.URL)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		http.Error(w, "Invalid Slack message URL format", http.StatusBadRequest)
+		return
 	}
 
-	req.Header.Set("Authorization", "Bearer "+slackBotToken)
-	req.URL.RawQuery = data.Encode()
+	messages, err := fetchMessagesForRef(ref)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting messages: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Ts < messages[j].Ts
+	})
 
-	resp, err := client.Do(req)
+	userIDs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		userIDs = append(userIDs, msg.User)
+	}
+	userNames := slackAPI.ResolveUsers(r.Context(), userIDs)
+
+	summarizeMessages := make([]summarize.Message, 0, len(messages))
+	for _, msg := range messages {
+		summarizeMessages = append(summarizeMessages, summarize.Message{
+			UserName: userNames[msg.User],
+			Text:     msg.Text,
+		})
+	}
+	prompt := summarize.BuildPrompt(summarizeMessages, mentionResolver{})
+
+	provider, err := llm.NewProvider(llm.ConfigFromEnv())
 	if err != nil {
-		return "", fmt.Errorf("failed to call slack api: %w", err)
+		http.Error(w, fmt.Sprintf("Error creating LLM provider: %v", err), http.StatusInternalServerError)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("slack api request failed with status: %s", resp.Status)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
 	}
 
-	var response struct {
-		OK    bool   `json:"ok"`
-		Error string `json:"error"`
-		User  struct {
-			Profile struct {
-				RealName string `json:"real_name"`
-			} `json:"profile"`
-		} `json:"user"`
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	var fullSummary strings.Builder
+	err = provider.Stream(r.Context(), prompt, func(delta string) error {
+		fullSummary.WriteString(delta)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(delta, "\n", "\\n")); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+		flusher.Flush()
+		return
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode slack api response: %w", err)
+	fmt.Fprintf(w, "event: done\ndata: {}\n\n")
+	flusher.Flush()
+
+	if r.URL.Query().Get("post_back") == "true" {
+		parentTs := ref.Ts
+		if ref.HasDistinctThread() {
+			parentTs = ref.ThreadTs
+		}
+		if err := postThreadReply(ref.ChannelID, parentTs, fullSummary.String()); err != nil {
+			log.Printf("failed to post summary back to thread: %v", err)
+		}
 	}
+}
 
-	if !response.OK {
-		return "", fmt.Errorf("slack api returned an error: %s", response.Error)
+// handleThreadStream スレッドの追記・編集・削除・リアクションの差分を Server-Sent Events で push するハンドラー。
+// Socket Mode が接続できていない場合は、一度だけ現在のスレッド内容を snapshot イベントとして返して終了する (REST フォールバック)
+func handleThreadStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ref, err := slackref.ParseSlackLink(r.URL.Query().Get("url"))
+	if err != nil {
+		http.Error(w, "Invalid Slack message URL format", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	if socketModeClient == nil || !socketModeClient.Connected() {
+		writeThreadSnapshot(w, flusher, ref)
+		return
+	}
+
+	parentTs := ref.Ts
+	if ref.HasDistinctThread() {
+		parentTs = ref.ThreadTs
+	}
+	key := ref.ChannelID + ":" + parentTs
+
+	events, unsubscribe := threadIndex.Subscribe(key)
+	defer unsubscribe()
+
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeThreadSnapshot Socket Mode が使えない場合のフォールバック。その時点のスレッド内容を 1 件の snapshot イベントとして送る
+func writeThreadSnapshot(w http.ResponseWriter, flusher http.Flusher, ref *slackref.SlackRef) {
+	messages, err := fetchMessagesForRef(ref)
+	if err != nil {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+		flusher.Flush()
+		return
+	}
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+		flusher.Flush()
+		return
+	}
+	fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data)
+	flusher.Flush()
+}
+
+// handleExportThread スレッドを JSON / Markdown / HTML / zip のいずれかでダウンロード可能な形にして返すハンドラー
+func handleExportThread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ref, err := slackref.ParseSlackLink(r.URL.Query().Get("url"))
+	if err != nil {
+		http.Error(w, "Invalid Slack message URL format", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	messages, err := fetchMessagesForRef(ref)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting messages: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Ts < messages[j].Ts
+	})
+
+	switch format {
+	case "json":
+		writeJSONExport(w, messages)
+	case "markdown":
+		writeRenderedExport(w, r, messages, render.FormatMarkdown, "text/markdown; charset=utf-8", "thread.md")
+	case "html":
+		writeRenderedExport(w, r, messages, render.FormatHTML, "text/html; charset=utf-8", "thread.html")
+	case "zip":
+		writeZipExport(w, r, messages)
+	default:
+		http.Error(w, "Unsupported format: must be json, markdown, html, or zip", http.StatusBadRequest)
+	}
+}
+
+// writeJSONExport blocks/files/reactions を含む SlackMessage をそのまま整形済み JSON としてダウンロードさせる
+func writeJSONExport(w http.ResponseWriter, messages []SlackMessage) {
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding export: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="thread.json"`)
+	w.Write(data)
+}
+
+// writeRenderedExport Markdown / HTML エクスポートの共通処理。添付ファイルはインライン (data URI) になるか、埋め込めなかった旨の注記になる (リンクは出さない)
+func writeRenderedExport(w http.ResponseWriter, r *http.Request, messages []SlackMessage, format render.Format, contentType, filename string) {
+	exportMessages, _, err := buildExportMessages(r.Context(), messages, format, false)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error building export: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	return response.User.Profile.RealName, nil
+	var body string
+	if format == render.FormatHTML {
+		body = export.HTML(exportMessages)
+	} else {
+		body = export.Markdown(exportMessages)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	fmt.Fprint(w, body)
+}
+
+// writeZipExport Markdown の transcript と添付ファイルの実体を 1 つの zip にまとめてダウンロードさせる
+func writeZipExport(w http.ResponseWriter, r *http.Request, messages []SlackMessage) {
+	exportMessages, attachmentFiles, err := buildExportMessages(r.Context(), messages, render.FormatMarkdown, true)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error building export: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	transcript := export.Markdown(exportMessages)
+	zipData, err := export.BuildZip("thread.md", transcript, attachmentFiles)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error building zip: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="thread.zip"`)
+	w.Write(zipData)
+}
+
+// buildExportMessages メッセージごとに本文のレンダリング・リアクション集計・添付ファイルの準備をまとめて行う。
+// forZip が true の場合、添付ファイルは種類・サイズによらず全てダウンロードして zip 同梱用のバイト列を集める
+func buildExportMessages(ctx context.Context, messages []SlackMessage, format render.Format, forZip bool) ([]export.Message, []export.AttachmentFile, error) {
+	resolver := mentionResolver{}
+
+	userIDs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		userIDs = append(userIDs, msg.User)
+	}
+	userNames := slackAPI.ResolveUsers(ctx, userIDs)
+
+	httpClient := &http.Client{}
+	var attachmentFiles []export.AttachmentFile
+	exportMessages := make([]export.Message, 0, len(messages))
+
+	for _, msg := range messages {
+		userName := userNames[msg.User]
+		if userName == "" {
+			userName = "Unknown"
+		}
+
+		formattedTimestamp, err := formatTimestamp(msg.Ts)
+		if err != nil {
+			formattedTimestamp = msg.Ts
+		}
+
+		body, err := render.RenderBlocks(msg.Blocks, msg.Text, format, resolver)
+		if err != nil {
+			body = msg.Text
+		}
+
+		exportMsg := export.Message{
+			Timestamp: formattedTimestamp,
+			UserName:  userName,
+			Body:      body,
+			Reactions: resolveReactions(msg.Reactions, resolver),
+		}
+
+		for _, file := range msg.Files {
+			attachment, data, err := export.PrepareAttachment(httpClient, slackBotToken, export.FileMeta{
+				ID:       file.ID,
+				Name:     file.Name,
+				Mimetype: file.Mimetype,
+			}, export.DefaultMaxInlineBytes, forZip)
+			if err != nil {
+				log.Printf("failed to prepare attachment %s: %v", file.ID, err)
+				continue
+			}
+			exportMsg.Attachments = append(exportMsg.Attachments, attachment)
+			if attachment.ZipName != "" {
+				attachmentFiles = append(attachmentFiles, export.AttachmentFile{ZipName: attachment.ZipName, Data: data})
+			}
+		}
+
+		exportMessages = append(exportMessages, exportMsg)
+	}
+
+	return exportMessages, attachmentFiles, nil
+}
+
+// mentionResolver render.MentionResolver を slackAPI クライアントで実装する。
+// 呼び出し元 (render パッケージ) はリクエストコンテキストを持たないため、ここでは Background を使う
+type mentionResolver struct{}
+
+func (mentionResolver) UserName(userID string) (string, error) {
+	return slackAPI.UserName(context.Background(), userID)
+}
+
+func (mentionResolver) ChannelName(channelID string) (string, error) {
+	return slackAPI.ChannelName(context.Background(), channelID)
+}
+
+func (mentionResolver) UsergroupName(usergroupID string) (string, error) {
+	return slackAPI.UsergroupName(context.Background(), usergroupID)
+}
+
+// resolveReactions SlackReaction の Users (ユーザー ID) を表示名に解決し、export.ReactionSummary に変換する
+func resolveReactions(reactions []SlackReaction, resolver mentionResolver) []export.ReactionSummary {
+	summaries := make([]export.ReactionSummary, 0, len(reactions))
+	for _, reaction := range reactions {
+		reactors := make([]string, 0, len(reaction.Users))
+		for _, userID := range reaction.Users {
+			name, err := resolver.UserName(userID)
+			if err != nil || name == "" {
+				name = userID
+			}
+			reactors = append(reactors, name)
+		}
+		summaries = append(summaries, export.ReactionSummary{
+			Emoji:    reaction.Name,
+			Count:    reaction.Count,
+			Reactors: reactors,
+		})
+	}
+	return summaries
 }
 
 // formatTimestamp タイムスタンプを日時にフォーマットする関数
@@ -228,17 +624,91 @@ func formatTimestamp(ts string) (string, error) {
 	return t.Format("2006-01-02 15:04:05"), nil
 }
 
-// extractSlackLinkInfo Slack のメッセージ URL からチャンネル ID とタイムスタンプを抽出する関数
-func extractSlackLinkInfo(link string) (string, string) {
-	re := regexp.MustCompile(`https:\/\/([a-zA-Z0-9-]+)\.slack\.com\/archives\/([CG][A-Za-z0-9]+)\/p([0-9]{10})([0-9]{6})`)
-	match := re.FindStringSubmatch(link)
-	// マッチした部分が 5 つの場合はチャンネル ID とタイムスタンプを返す
-	if len(match) == 5 {
-		channelID := match[2]
-		timestamp := fmt.Sprintf("%s.%s", match[3], match[4])
-		return channelID, timestamp
-	}
-	return "", ""
+// getStandaloneMessage conversations.history を使って、スレッドの親ではない単発メッセージ (主に DM) を 1 件だけ取得する
+func getStandaloneMessage(channelID, ts string) ([]SlackMessage, error) {
+	ctx := context.Background()
+	client := &http.Client{}
+	apiURL := "https://slack.com/api/conversations.history"
+
+	data := url.Values{}
+	data.Set("channel", channelID)
+	data.Set("latest", ts)
+	data.Set("oldest", ts)
+	data.Set("inclusive", "true")
+	data.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+slackBotToken)
+	req.URL.RawQuery = data.Encode()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call slack api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("slack api request failed with status: %s", resp.Status)
+	}
+
+	var slackResponse SlackMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&slackResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode slack api response: %w", err)
+	}
+	if !slackResponse.OK {
+		return nil, fmt.Errorf("slack api returned an error: %v", slackResponse.Error)
+	}
+
+	return slackResponse.Messages, nil
+}
+
+// postThreadReply chat.postMessage を使って、要約をスレッドへの返信として投稿する
+func postThreadReply(channelID, threadTs, text string) error {
+	body, err := json.Marshal(struct {
+		Channel  string `json:"channel"`
+		ThreadTs string `json:"thread_ts"`
+		Text     string `json:"text"`
+	}{
+		Channel:  channelID,
+		ThreadTs: threadTs,
+		Text:     text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode chat.postMessage request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://slack.com/api/chat.postMessage", strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+slackBotToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call slack api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack api request failed with status: %s", resp.Status)
+	}
+
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode slack api response: %w", err)
+	}
+	if !response.OK {
+		return fmt.Errorf("slack api returned an error: %s", response.Error)
+	}
+	return nil
 }
 
 // getThreadMessages 指定されたチャンネルと親メッセージのタイムスタンプから、スレッド内の全てのメッセージを取得する関数